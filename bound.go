@@ -0,0 +1,85 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// truncatingReader wraps an io.ReadCloser, reading at most max bytes from
+// it. If the wrapped reader still has data left once max bytes have been
+// read, truncated is set to true.
+type truncatingReader struct {
+	io.ReadCloser
+	max, read int64
+	truncated bool
+}
+
+func (t *truncatingReader) Read(p []byte) (int, error) {
+	if t.read >= t.max {
+		var probe [1]byte
+		if n, _ := t.ReadCloser.Read(probe[:]); n > 0 {
+			t.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if limit := t.max - t.read; int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := t.ReadCloser.Read(p)
+	t.read += int64(n)
+	return n, err
+}
+
+// timeoutBody wraps body so that it is closed -- aborting any Read that is
+// in flight or still to come -- if it has not been fully read within
+// timeout. It is a no-op when timeout is zero.
+func timeoutBody(body io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if timeout <= 0 {
+		return body
+	}
+	t := &timeoutReadCloser{ReadCloser: body}
+	t.timer = time.AfterFunc(timeout, func() { body.Close() })
+	return t
+}
+
+type timeoutReadCloser struct {
+	io.ReadCloser
+	timer *time.Timer
+}
+
+func (t *timeoutReadCloser) Close() error {
+	t.timer.Stop()
+	return t.ReadCloser.Close()
+}
+
+// stopTimeoutTimer disarms the timer of the timeoutReadCloser wrapped
+// somewhere inside body, if any, without closing body itself. Callers that
+// take resp.Body over from CheckResponse -- e.g. CheckResponsePreserveBody
+// -- need this to stop a pending timeoutBody deadline from firing and
+// closing the body out from under them after they've taken it over.
+func stopTimeoutTimer(body io.ReadCloser) {
+	switch b := body.(type) {
+	case *timeoutReadCloser:
+		b.timer.Stop()
+	case *truncatingReader:
+		stopTimeoutTimer(b.ReadCloser)
+	}
+}
+
+// appendTruncationNotice appends a marker to msg recording that the body
+// extractor stopped reading after max bytes, so that the extracted message
+// doesn't silently look complete when it isn't. max is how much was kept,
+// not how much was discarded, which truncatingReader has no way to know.
+func appendTruncationNotice(msg string, max int64) string {
+	notice := fmt.Sprintf("… [truncated, read %d bytes]", max)
+	if msg == "" {
+		return notice
+	}
+	return msg + " " + notice
+}
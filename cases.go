@@ -0,0 +1,58 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Case pairs a predicate over a response with a diagnostic message to
+// surface in its place. See Cases.
+type Case struct {
+	// Match reports whether this case applies to resp.
+	Match func(resp *http.Response) bool
+
+	// Message returns the diagnostic to use in place of resp's body when
+	// Match(resp) is true.
+	Message func(resp *http.Response) string
+}
+
+// Cases returns a ResponseChecker that rejects resp -- regardless of its
+// status code -- if it matches one of cases (tried in order, first match
+// wins), replacing its body with that case's diagnostic message before
+// CheckResponse extracts it. This lets library authors ship domain-
+// specific hints for common misuse patterns -- e.g. "server returned an
+// HTML login page -- did your session expire?", which can just as well
+// come back with a 200 as with a 4xx -- instead of just surfacing whatever
+// the raw server body happens to contain.
+//
+// A resp that matches none of cases is handled exactly like
+// DefaultResponseChecker would, body and all.
+func Cases(cases []Case) ResponseChecker {
+	return func(resp *http.Response) bool {
+		for _, c := range cases {
+			if !c.Match(resp) {
+				continue
+			}
+			msg := c.Message(resp)
+			header := resp.Header.Clone()
+			if header == nil {
+				header = http.Header{}
+			}
+			header.Set("Content-Type", "text/plain; charset=utf-8")
+			resp.Header = header
+			if resp.Body != nil {
+				resp.Body.Close()
+			}
+			resp.Body = io.NopCloser(strings.NewReader(msg))
+			return false
+		}
+
+		return DefaultResponseChecker(resp)
+	}
+}
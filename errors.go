@@ -6,24 +6,39 @@
 package hterrors
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"mime"
 	"net/http"
 	"net/url"
-	"regexp"
-	"sort"
 	"strings"
-
-	"github.com/k3a/html2text"
 )
 
 // StatusError represents a non-2xx HTTP status code, and the associated message
-// returned by the server, if any.
+// returned by the server, if any. Callers can match it against a status
+// class with errors.Is, e.g. errors.Is(err, hterrors.ErrNotFound) or
+// errors.Is(err, hterrors.ErrServerError); see also Retryable and RetryAfter.
 type StatusError struct {
 	StatusCode int
 	Message    string
+
+	// Detail holds the structured error payload extracted from the response
+	// body or trailers, if the content type was recognized as such (e.g.
+	// *ProblemError or *GRPCStatusError). It is nil otherwise.
+	Detail error
+
+	// Header holds the header of the response that caused this error, so
+	// that e.g. RetryAfter can read Retry-After off of it.
+	Header http.Header
+
+	// RawBody holds the raw bytes of the response body that were read to
+	// build Message and Detail, if the error was produced by
+	// CheckResponsePreserveBody. It is nil otherwise.
+	RawBody []byte
+}
+
+// Unwrap returns the structured error payload held in Detail, if any. This
+// allows callers to retrieve it with errors.As.
+func (err *StatusError) Unwrap() error {
+	return err.Detail
 }
 
 // ResponseChecker represents a function that accepts or rejects a response
@@ -51,74 +66,26 @@ func (err *StatusError) Error() string {
 	}
 }
 
-var (
-	nlre  = regexp.MustCompile(`(\r?\n)+`)
-	space = regexp.MustCompile(`\s\s+`)
-)
-
-func extractMessage(resp *http.Response) string {
-	mtype, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
-	if err != nil {
-		// assume text
-		mtype = "text/plain"
-	}
-
-	// The MIME type might be a vendor type, which looks like application/vnd.*+type;
-	// in which case we try to change it back to the appropriate application/type
-	// MIME.
-	// This isn't always correct, but is a good enough heuristic for most API
-	// bodies.
-	if strings.HasPrefix(mtype, "application/vnd.") {
-		if i := strings.IndexRune(mtype, '+'); i != -1 {
-			mtype = "application/" + mtype[i+1:]
-		}
-	}
-
-	switch mtype {
-	case "text/plain":
-		var out strings.Builder
-		io.Copy(&out, resp.Body)
-		return out.String()
-
-	case "text/html":
-		var out strings.Builder
-		io.Copy(&out, resp.Body)
-		body := strings.TrimSpace(html2text.HTML2Text(out.String()))
-		return space.ReplaceAllString(nlre.ReplaceAllString(body, ": "), " ")
-
-	case "application/json":
-		var doc map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
-			return fmt.Sprintf("<invalid json in response body: %v>", err)
-		}
-
-		keys := make([]string, 0, len(doc))
-		for k := range doc {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-
-		fields := make([]string, 0, len(doc))
-		for _, k := range keys {
-			fields = append(fields, fmt.Sprintf("%s: %v", k, doc[k]))
-		}
-		return strings.Join(fields, ", ")
-
-	default:
-		return ""
-	}
-}
-
 // CheckResponse returns an error if the response is rejected by
 // the specified response checker. The returned error contains a digested
 // version of the response body, and the response body is consumed.
-func CheckResponse(resp *http.Response, checker ResponseChecker) error {
+//
+// By default, the message and structured Detail attached to the returned
+// *StatusError are extracted using DefaultRegistry, reading at most 1 MiB
+// of the response body; pass WithRegistry, WithMaxBodyBytes or
+// WithReadTimeout to override these defaults.
+func CheckResponse(resp *http.Response, checker ResponseChecker, opts ...Option) error {
 	if checker(resp) {
 		return nil
 	}
 
-	msg := extractMessage(resp)
-	err := &StatusError{StatusCode: resp.StatusCode, Message: msg}
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	msg, detail := extractMessage(resp, cfg)
+	err := &StatusError{StatusCode: resp.StatusCode, Message: msg, Detail: detail, Header: resp.Header}
 
 	if resp.Request == nil {
 		return &url.Error{
@@ -1,11 +1,13 @@
 package hterrors
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestJSONResponse(t *testing.T) {
@@ -27,3 +29,433 @@ func TestJSONResponse(t *testing.T) {
 		t.Errorf("Expected error message to contain fields from JSON response; instead got %q", err.Error())
 	}
 }
+
+func TestProblemJSONResponse(t *testing.T) {
+	err := CheckStatus(&http.Response{
+		StatusCode: http.StatusNotFound,
+		Header: http.Header{
+			"Content-Type": []string{"application/problem+json"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{
+			"type": "https://example.com/probs/out-of-stock",
+			"title": "Item out of stock",
+			"detail": "Item B00027Y5QG is no longer available",
+			"instance": "/orders/12345",
+			"stock_remaining": 0
+		}`)),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "Item B00027Y5QG is no longer available") {
+		t.Errorf("Expected error message to contain the problem detail; instead got %q", err.Error())
+	}
+
+	var prob *ProblemError
+	if !errors.As(err, &prob) {
+		t.Fatalf("Expected errors.As to find a *ProblemError in %v", err)
+	}
+	if prob.Type != "https://example.com/probs/out-of-stock" {
+		t.Errorf("Expected problem type to be set; got %q", prob.Type)
+	}
+	if prob.Instance != "/orders/12345" {
+		t.Errorf("Expected problem instance to be set; got %q", prob.Instance)
+	}
+	if prob.Extensions["stock_remaining"] != 0.0 {
+		t.Errorf("Expected stock_remaining extension to be set; got %v", prob.Extensions["stock_remaining"])
+	}
+}
+
+func TestGRPCStatusJSONResponse(t *testing.T) {
+	err := CheckStatus(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header: http.Header{
+			"Content-Type": []string{"application/grpc-status+json"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{
+			"code": 5,
+			"message": "user not found",
+			"details": [{"@type": "type.googleapis.com/google.rpc.ResourceInfo", "resource_name": "users/42"}]
+		}`)),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "user not found") {
+		t.Errorf("Expected error message to contain the gRPC message; instead got %q", err.Error())
+	}
+
+	var gerr *GRPCStatusError
+	if !errors.As(err, &gerr) {
+		t.Fatalf("Expected errors.As to find a *GRPCStatusError in %v", err)
+	}
+	if gerr.Code != 5 {
+		t.Errorf("Expected gRPC code 5; got %d", gerr.Code)
+	}
+	if len(gerr.Details) != 1 || gerr.Details[0].Type != "type.googleapis.com/google.rpc.ResourceInfo" {
+		t.Errorf("Expected one detail with the resource info type; got %+v", gerr.Details)
+	}
+}
+
+func TestWithRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("application/json", func(resp *http.Response) (string, error) {
+		return "custom extractor", nil
+	})
+
+	err := CheckResponse(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"foo": "bar"}`)),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, DefaultResponseChecker, WithRegistry(registry))
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "custom extractor") {
+		t.Errorf("Expected error message from the custom registry; instead got %q", err.Error())
+	}
+}
+
+func TestStatusErrorIs(t *testing.T) {
+	err := CheckStatus(&http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	})
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected errors.Is(err, ErrNotFound) to hold")
+	}
+	if !errors.Is(err, ErrClientError) {
+		t.Errorf("Expected errors.Is(err, ErrClientError) to hold")
+	}
+	if errors.Is(err, ErrServerError) {
+		t.Errorf("Expected errors.Is(err, ErrServerError) to not hold")
+	}
+	if errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected errors.Is(err, ErrForbidden) to not hold")
+	}
+}
+
+func TestStatusErrorRetryAfter(t *testing.T) {
+	err := CheckStatus(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header: http.Header{
+			"Retry-After": []string{"120"},
+		},
+		Body: io.NopCloser(strings.NewReader("")),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	})
+
+	if !errors.Is(err, ErrRetryable) {
+		t.Errorf("Expected errors.Is(err, ErrRetryable) to hold for a 503")
+	}
+
+	var serr *StatusError
+	if !errors.As(err, &serr) {
+		t.Fatalf("Expected errors.As to find a *StatusError in %v", err)
+	}
+	if !serr.Retryable() {
+		t.Errorf("Expected Retryable() to be true for a 503")
+	}
+	if d, ok := serr.RetryAfter(); !ok || d != 120*time.Second {
+		t.Errorf("Expected RetryAfter to be 120s, true; got %v, %v", d, ok)
+	}
+}
+
+func TestStatusErrorRetryableExcludesNonRetryable5xx(t *testing.T) {
+	for _, code := range []int{
+		http.StatusNotImplemented,
+		http.StatusHTTPVersionNotSupported,
+		http.StatusNetworkAuthenticationRequired,
+	} {
+		err := CheckStatus(&http.Response{
+			StatusCode: code,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request: &http.Request{
+				Method: "GET",
+				URL:    &url.URL{},
+			},
+		})
+
+		if errors.Is(err, ErrRetryable) {
+			t.Errorf("Expected errors.Is(err, ErrRetryable) to not hold for %d", code)
+		}
+
+		var serr *StatusError
+		if !errors.As(err, &serr) {
+			t.Fatalf("Expected errors.As to find a *StatusError in %v", err)
+		}
+		if serr.Retryable() {
+			t.Errorf("Expected Retryable() to be false for %d", code)
+		}
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	err := CheckResponse(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header: http.Header{
+			"Content-Type": []string{"text/plain"},
+		},
+		Body: io.NopCloser(strings.NewReader("0123456789")),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, DefaultResponseChecker, WithMaxBodyBytes(4))
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "0123") {
+		t.Errorf("Expected error message to contain the first 4 bytes; instead got %q", err.Error())
+	}
+	if !strings.Contains(err.Error(), "truncated, read 4 bytes") {
+		t.Errorf("Expected error message to mention the truncation; instead got %q", err.Error())
+	}
+}
+
+func TestCheckResponsePreserveBody(t *testing.T) {
+	resp, err := CheckResponsePreserveBody(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"foo": "bar"}`)),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, DefaultResponseChecker)
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+
+	var serr *StatusError
+	if !errors.As(err, &serr) {
+		t.Fatalf("Expected errors.As to find a *StatusError in %v", err)
+	}
+	if string(serr.RawBody) != `{"foo": "bar"}` {
+		t.Errorf("Expected RawBody to hold the full response body; got %q", serr.RawBody)
+	}
+
+	replayed, readErr := io.ReadAll(resp.Body)
+	if readErr != nil {
+		t.Fatalf("Expected to be able to read resp.Body again; got %v", readErr)
+	}
+	if string(replayed) != `{"foo": "bar"}` {
+		t.Errorf("Expected resp.Body to still be readable; got %q", replayed)
+	}
+}
+
+func TestCheckResponsePreserveBodyStopsReadTimeoutTimer(t *testing.T) {
+	body := &closeTrackingReader{Reader: strings.NewReader(`{"foo": "bar"}`)}
+	resp, err := CheckResponsePreserveBody(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+		},
+		Body: body,
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, DefaultResponseChecker, WithReadTimeout(10*time.Millisecond))
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+
+	// If the timeoutBody timer armed during extraction wasn't disarmed,
+	// it would fire around here and close resp.Body out from under us.
+	time.Sleep(50 * time.Millisecond)
+
+	if body.closed {
+		t.Errorf("Expected the read timeout timer to be disarmed; it closed the original response body")
+	}
+
+	replayed, readErr := io.ReadAll(resp.Body)
+	if readErr != nil {
+		t.Fatalf("Expected resp.Body to still be readable after the read timeout elapsed; got %v", readErr)
+	}
+	if string(replayed) != `{"foo": "bar"}` {
+		t.Errorf("Expected resp.Body to still be readable; got %q", replayed)
+	}
+}
+
+func TestCases(t *testing.T) {
+	checker := Cases([]Case{
+		{
+			Match: func(resp *http.Response) bool {
+				return resp.Header.Get("Content-Type") == "text/html"
+			},
+			Message: func(resp *http.Response) string {
+				return "server returned an HTML login page -- did your session expire?"
+			},
+		},
+	})
+
+	err := CheckResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": []string{"text/html"},
+		},
+		Body: io.NopCloser(strings.NewReader("<html><body>Please log in</body></html>")),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, checker)
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "did your session expire?") {
+		t.Errorf("Expected error message to contain the case diagnostic; instead got %q", err.Error())
+	}
+}
+
+func TestCasesClosesOriginalBodyOnMatch(t *testing.T) {
+	body := &closeTrackingReader{Reader: strings.NewReader("<html><body>Please log in</body></html>")}
+	checker := Cases([]Case{
+		{
+			Match: func(resp *http.Response) bool {
+				return resp.Header.Get("Content-Type") == "text/html"
+			},
+			Message: func(resp *http.Response) string {
+				return "server returned an HTML login page -- did your session expire?"
+			},
+		},
+	})
+
+	err := CheckResponse(&http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": []string{"text/html"},
+		},
+		Body: body,
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, checker)
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !body.closed {
+		t.Errorf("Expected the original response body to be closed once a case replaced it")
+	}
+}
+
+type closeTrackingReader struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTrackingReader) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCasesMatchWithNilHeader(t *testing.T) {
+	checker := Cases([]Case{
+		{
+			Match: func(resp *http.Response) bool {
+				return resp.StatusCode == http.StatusInternalServerError
+			},
+			Message: func(resp *http.Response) string {
+				return "upstream is on fire"
+			},
+		},
+	})
+
+	err := CheckResponse(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       io.NopCloser(strings.NewReader("boom")),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, checker)
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "upstream is on fire") {
+		t.Errorf("Expected error message to contain the case diagnostic; instead got %q", err.Error())
+	}
+}
+
+func TestCasesNoMatchFallsBackToBody(t *testing.T) {
+	checker := Cases([]Case{
+		{
+			Match:   func(resp *http.Response) bool { return false },
+			Message: func(resp *http.Response) string { return "unreachable" },
+		},
+	})
+
+	err := CheckResponse(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header: http.Header{
+			"Content-Type": []string{"text/plain"},
+		},
+		Body: io.NopCloser(strings.NewReader("boom")),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	}, checker)
+
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected error message to contain the raw body; instead got %q", err.Error())
+	}
+}
+
+func TestVendorSuffixWildcard(t *testing.T) {
+	err := CheckStatus(&http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header: http.Header{
+			"Content-Type": []string{"application/vnd.github+json"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"foo": "bar"}`)),
+		Request: &http.Request{
+			Method: "GET",
+			URL:    &url.URL{},
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected error; got nil")
+	}
+	if !strings.Contains(err.Error(), "foo: bar") {
+		t.Errorf("Expected the *+json wildcard extractor to handle the vendor type; instead got %q", err.Error())
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/k3a/html2text"
+)
+
+var (
+	nlre  = regexp.MustCompile(`(\r?\n)+`)
+	space = regexp.MustCompile(`\s\s+`)
+)
+
+// extractMessage digests the body (or, for gRPC-web responses, the
+// trailers) of resp into a human-readable message, along with a structured
+// detail error when registry has an Extractor for the response's media
+// type that produces one.
+//
+// The body is read under cfg's MaxBodyBytes and ReadTimeout, appending a
+// truncation marker to msg if the limit was hit before the extractor
+// reached the end of the body.
+func extractMessage(resp *http.Response, cfg config) (msg string, detail error) {
+	mtype, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		// assume text
+		mtype = "text/plain"
+	}
+
+	if fn, ok := cfg.registry.Lookup(mtype); ok {
+		body := timeoutBody(resp.Body, cfg.readTimeout)
+		var tr *truncatingReader
+		if cfg.maxBodyBytes > 0 {
+			tr = &truncatingReader{ReadCloser: body, max: cfg.maxBodyBytes}
+			body = tr
+		}
+		resp.Body = body
+
+		msg, detail = fn(resp)
+		if tr != nil && tr.truncated {
+			msg = appendTruncationNotice(msg, tr.max)
+		}
+	}
+
+	// grpc-web responses carry their status in the Grpc-Status/Grpc-Message
+	// trailers rather than in the body; prefer them over whatever the body
+	// (if any) contained, since they are the authoritative source.
+	if gerr := grpcTrailerStatus(resp); gerr != nil {
+		msg, detail = gerr.Error(), gerr
+	}
+
+	return msg, detail
+}
+
+func extractText(resp *http.Response) (string, error) {
+	var out strings.Builder
+	io.Copy(&out, resp.Body)
+	return out.String(), nil
+}
+
+func extractHTML(resp *http.Response) (string, error) {
+	var out strings.Builder
+	io.Copy(&out, resp.Body)
+	body := strings.TrimSpace(html2text.HTML2Text(out.String()))
+	return space.ReplaceAllString(nlre.ReplaceAllString(body, ": "), " "), nil
+}
+
+func extractJSON(resp *http.Response) (string, error) {
+	var doc map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Sprintf("<invalid json in response body: %v>", err), nil
+	}
+
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(doc))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s: %v", k, doc[k]))
+	}
+	return strings.Join(fields, ", "), nil
+}
+
+// xmlNode is a generic XML element, used to flatten an arbitrary document
+// into "tag: text" fields similar to extractJSON.
+type xmlNode struct {
+	XMLName xml.Name
+	Content string    `xml:",chardata"`
+	Nodes   []xmlNode `xml:",any"`
+}
+
+func (n xmlNode) flatten(prefix string, fields *[]string) {
+	name := n.XMLName.Local
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+
+	if len(n.Nodes) == 0 {
+		if text := strings.TrimSpace(n.Content); text != "" {
+			*fields = append(*fields, fmt.Sprintf("%s: %s", name, text))
+		}
+		return
+	}
+	for _, child := range n.Nodes {
+		child.flatten(name, fields)
+	}
+}
+
+func extractXML(resp *http.Response) (string, error) {
+	var doc xmlNode
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Sprintf("<invalid xml in response body: %v>", err), nil
+	}
+
+	var fields []string
+	doc.flatten("", &fields)
+	sort.Strings(fields)
+	return strings.Join(fields, ", "), nil
+}
+
+func extractProblem(resp *http.Response) (string, error) {
+	prob, err := decodeProblem(resp.Body)
+	if err != nil {
+		return fmt.Sprintf("<invalid json in response body: %v>", err), nil
+	}
+	return prob.Error(), prob
+}
+
+func extractGRPCStatus(resp *http.Response) (string, error) {
+	gerr, err := decodeGRPCStatus(resp.Body)
+	if err != nil {
+		return fmt.Sprintf("<invalid json in response body: %v>", err), nil
+	}
+	return gerr.Error(), gerr
+}
+
+// grpcTrailerStatus builds a GRPCStatusError from the Grpc-Status and
+// Grpc-Message trailers of resp, as set by grpc-web servers. It returns nil
+// if the response carries no such trailer.
+func grpcTrailerStatus(resp *http.Response) *GRPCStatusError {
+	code := resp.Trailer.Get("Grpc-Status")
+	if code == "" {
+		return nil
+	}
+	status, _ := strconv.Atoi(code)
+	return &GRPCStatusError{Code: status, Message: resp.Trailer.Get("Grpc-Message")}
+}
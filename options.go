@@ -0,0 +1,62 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import "time"
+
+// defaultMaxBodyBytes is the default value of config.maxBodyBytes: large
+// enough to hold any reasonable error body, small enough that a hostile or
+// misbehaving server can't use it to exhaust client memory.
+const defaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// config holds the resolved settings of a CheckResponse call, built by
+// applying the Option values passed by the caller on top of the defaults.
+type config struct {
+	registry     *Registry
+	maxBodyBytes int64
+	readTimeout  time.Duration
+}
+
+func defaultConfig() config {
+	return config{
+		registry:     DefaultRegistry,
+		maxBodyBytes: defaultMaxBodyBytes,
+	}
+}
+
+// Option customizes the behaviour of CheckResponse.
+type Option func(*config)
+
+// WithRegistry makes CheckResponse use registry instead of DefaultRegistry
+// to extract the error message and structured detail from the response
+// body. This lets independent API clients in the same process register
+// their own set of extractors without stepping on each other's global
+// state.
+func WithRegistry(registry *Registry) Option {
+	return func(c *config) {
+		c.registry = registry
+	}
+}
+
+// WithMaxBodyBytes caps the number of response body bytes that CheckResponse
+// will read when building the error message and structured detail, to
+// protect callers against huge or unbounded bodies returned by a hostile or
+// misconfigured server. When the limit is reached, a truncation marker is
+// appended to the extracted message. The default is 1 MiB; a value <= 0
+// disables the limit.
+func WithMaxBodyBytes(n int64) Option {
+	return func(c *config) {
+		c.maxBodyBytes = n
+	}
+}
+
+// WithReadTimeout bounds how long CheckResponse will spend reading the
+// response body before giving up on it. There is no timeout by default.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.readTimeout = timeout
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// teeBody wraps an io.ReadCloser, capturing every byte read through it into
+// buf, so that it can be replayed once the original reader has been read
+// however far a body extractor took it.
+type teeBody struct {
+	io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// replayBody is an io.ReadCloser that yields prefix followed by whatever
+// remains unread on closer, and closes closer on Close.
+type replayBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (r *replayBody) Close() error {
+	return r.closer.Close()
+}
+
+// CheckResponsePreserveBody behaves like CheckResponse, except that it
+// doesn't leave resp.Body fully consumed: the bytes read while extracting
+// the error message are captured and, together with whatever of the body
+// remains unread, replayed through the returned response's Body. This lets
+// callers also decode a partial success payload or log the raw bytes, which
+// plain CheckResponse's full consumption of resp.Body precludes.
+//
+// The captured bytes are also attached to the returned *StatusError's
+// RawBody field. RawBody holds exactly the bytes the body extractor read:
+// the whole body only if the extractor read it to its end within the
+// configured MaxBodyBytes, and nothing if no extractor is registered for
+// the response's media type. The body is not read any further than the
+// extractor took it. The returned Body replays those captured bytes and
+// then continues with the unread remainder of the underlying stream.
+func CheckResponsePreserveBody(resp *http.Response, checker ResponseChecker, opts ...Option) (*http.Response, error) {
+	tee := &teeBody{ReadCloser: resp.Body}
+	resp.Body = tee
+
+	err := CheckResponse(resp, checker, opts...)
+
+	// CheckResponse may have wrapped resp.Body in a timeoutBody to bound
+	// the read; now that extraction is done, disarm its timer so it
+	// doesn't fire later and close the body out from under the replay
+	// reader below.
+	stopTimeoutTimer(resp.Body)
+
+	resp.Body = &replayBody{
+		Reader: io.MultiReader(bytes.NewReader(tee.buf.Bytes()), tee.ReadCloser),
+		closer: tee.ReadCloser,
+	}
+
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		serr.RawBody = tee.buf.Bytes()
+	}
+
+	return resp, err
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProblemError represents the structured payload of an RFC 7807
+// application/problem+json response. It is attached to the StatusError
+// returned by CheckResponse and can be retrieved with errors.As.
+type ProblemError struct {
+	// Type is a URI reference that identifies the problem type.
+	Type string
+	// Title is a short, human-readable summary of the problem type.
+	Title string
+	// Detail is a human-readable explanation specific to this occurrence
+	// of the problem.
+	Detail string
+	// Instance is a URI reference that identifies the specific occurrence
+	// of the problem.
+	Instance string
+	// Status is the HTTP status code repeated by the server in the body,
+	// if any.
+	Status int
+	// Extensions holds any member of the problem object that isn't one of
+	// the standard RFC 7807 fields above.
+	Extensions map[string]interface{}
+}
+
+func (err *ProblemError) Error() string {
+	switch {
+	case err.Detail != "":
+		return err.Detail
+	case err.Title != "":
+		return err.Title
+	case err.Type != "":
+		return err.Type
+	default:
+		return "problem+json error"
+	}
+}
+
+// GRPCStatusError represents the structured payload of a grpc-gateway style
+// application/grpc-status+json response, or of the Grpc-Status/Grpc-Message
+// trailers of a grpc-web response. It is attached to the StatusError
+// returned by CheckResponse and can be retrieved with errors.As.
+type GRPCStatusError struct {
+	// Code is the gRPC status code of the response.
+	Code int
+	// Message is the human-readable error message returned by the server.
+	Message string
+	// Details holds the contents of the `details` array, if any.
+	Details []GRPCErrorDetail
+}
+
+// GRPCErrorDetail represents one entry of the `details` array of a
+// grpc-gateway error response.
+type GRPCErrorDetail struct {
+	// Type is the value of the detail's `@type` member.
+	Type string
+	// Payload holds the remaining members of the detail object.
+	Payload map[string]interface{}
+}
+
+func (err *GRPCStatusError) Error() string {
+	if err.Message != "" {
+		return err.Message
+	}
+	return fmt.Sprintf("grpc status %d", err.Code)
+}
+
+// decodeProblem decodes an RFC 7807 application/problem+json body into a
+// ProblemError, keeping any member that isn't one of the standard fields in
+// its Extensions.
+func decodeProblem(body io.Reader) (*ProblemError, error) {
+	var doc map[string]interface{}
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	prob := &ProblemError{}
+	for k, v := range doc {
+		switch k {
+		case "type":
+			prob.Type, _ = v.(string)
+		case "title":
+			prob.Title, _ = v.(string)
+		case "detail":
+			prob.Detail, _ = v.(string)
+		case "instance":
+			prob.Instance, _ = v.(string)
+		case "status":
+			if f, ok := v.(float64); ok {
+				prob.Status = int(f)
+			}
+		default:
+			if prob.Extensions == nil {
+				prob.Extensions = make(map[string]interface{})
+			}
+			prob.Extensions[k] = v
+		}
+	}
+	return prob, nil
+}
+
+// decodeGRPCStatus decodes a grpc-gateway style application/grpc-status+json
+// body into a GRPCStatusError.
+func decodeGRPCStatus(body io.Reader) (*GRPCStatusError, error) {
+	var doc struct {
+		Code    int                      `json:"code"`
+		Message string                   `json:"message"`
+		Details []map[string]interface{} `json:"details"`
+	}
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	gerr := &GRPCStatusError{Code: doc.Code, Message: doc.Message}
+	for _, d := range doc.Details {
+		detail := GRPCErrorDetail{}
+		for k, v := range d {
+			if k == "@type" {
+				detail.Type, _ = v.(string)
+				continue
+			}
+			if detail.Payload == nil {
+				detail.Payload = make(map[string]interface{})
+			}
+			detail.Payload[k] = v
+		}
+		gerr.Details = append(gerr.Details, detail)
+	}
+	return gerr, nil
+}
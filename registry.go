@@ -0,0 +1,95 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Extractor digests the body of a failing response into a human-readable
+// message, along with a structured detail error when the media type carries
+// one (e.g. *ProblemError). detail is nil when there is no such structured
+// representation.
+type Extractor func(resp *http.Response) (msg string, detail error)
+
+// Registry associates media types with the Extractor responsible for
+// digesting response bodies of that type.
+//
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{extractors: make(map[string]Extractor)}
+}
+
+// Register associates fn with mediaType, so that CheckResponse calls fn to
+// build the error message and detail of a failing response whose
+// Content-Type is mediaType.
+//
+// mediaType may be a structured syntax suffix wildcard of the form
+// "*+json" or "*+xml" (see RFC 6839), which matches any media type ending
+// in that suffix that has no more specific registration, e.g.
+// "application/vnd.github+json" or "application/problem+json" both fall
+// back to "*+json" if neither is registered explicitly.
+func (r *Registry) Register(mediaType string, fn Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors[mediaType] = fn
+}
+
+// Lookup returns the Extractor registered for mediaType, falling back to a
+// structured syntax suffix wildcard (see Register) if there is no exact
+// match. It returns false if neither is registered.
+func (r *Registry) Lookup(mediaType string) (Extractor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if fn, ok := r.extractors[mediaType]; ok {
+		return fn, true
+	}
+	if i := strings.IndexByte(mediaType, '+'); i != -1 {
+		if fn, ok := r.extractors["*"+mediaType[i:]]; ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// DefaultRegistry is the Registry consulted by CheckResponse when no
+// WithRegistry option is given. It comes pre-populated with extractors for
+// text/plain, text/html, application/json, application/xml,
+// application/problem+json and application/grpc-status+json; register
+// additional media types on it with RegisterExtractor.
+var DefaultRegistry = NewRegistry()
+
+// RegisterExtractor registers fn as the body extractor for mediaType on
+// DefaultRegistry. See Registry.Register for the accepted forms of
+// mediaType.
+//
+// Callers that don't want to mutate global state -- for instance because
+// they maintain several API clients with different error formats in the
+// same process -- should build their own Registry instead and pass it to
+// CheckResponse with WithRegistry.
+func RegisterExtractor(mediaType string, fn func(resp *http.Response) (msg string, detail error)) {
+	DefaultRegistry.Register(mediaType, fn)
+}
+
+func init() {
+	DefaultRegistry.Register("text/plain", extractText)
+	DefaultRegistry.Register("text/html", extractHTML)
+	DefaultRegistry.Register("application/json", extractJSON)
+	DefaultRegistry.Register("application/xml", extractXML)
+	DefaultRegistry.Register("application/problem+json", extractProblem)
+	DefaultRegistry.Register("application/grpc-status+json", extractGRPCStatus)
+	DefaultRegistry.Register("*+json", extractJSON)
+	DefaultRegistry.Register("*+xml", extractXML)
+}
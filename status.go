@@ -0,0 +1,195 @@
+// Copyright 2020 - 2021, Franklin "Snaipe" Mathieu <me@snai.pe>
+//
+// Use of this source-code is govered by the MIT license, which
+// can be found in the LICENSE file.
+
+package hterrors
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusError is a sentinel error matched by *StatusError.Is. It never
+// surfaces as the actual error returned by CheckResponse; it only exists to
+// be compared against with errors.Is.
+type statusError struct {
+	text  string
+	match func(code int) bool
+}
+
+func (err *statusError) Error() string {
+	return err.text
+}
+
+func newStatusError(code int) *statusError {
+	return &statusError{
+		text:  http.StatusText(code),
+		match: func(c int) bool { return c == code },
+	}
+}
+
+// ErrClientError matches any *StatusError whose StatusCode is in the 4xx
+// range, for use with errors.Is.
+var ErrClientError = &statusError{
+	text:  "client error",
+	match: func(code int) bool { return code >= 400 && code < 500 },
+}
+
+// ErrServerError matches any *StatusError whose StatusCode is in the 5xx
+// range, for use with errors.Is.
+var ErrServerError = &statusError{
+	text:  "server error",
+	match: func(code int) bool { return code >= 500 && code < 600 },
+}
+
+// ErrRetryable matches any *StatusError whose StatusCode is one that's
+// generally safe to retry -- 408 Request Timeout, 425 Too Early, 429 Too
+// Many Requests, 500 Internal Server Error, 502 Bad Gateway, 503 Service
+// Unavailable, or 504 Gateway Timeout -- for use with errors.Is. See also
+// (*StatusError).Retryable.
+var ErrRetryable = &statusError{
+	text:  "retryable error",
+	match: isRetryableStatus,
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooEarly,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sentinel errors for every named status in net/http, for use with
+// errors.Is, e.g. errors.Is(err, hterrors.ErrNotFound).
+var (
+	ErrContinue           = newStatusError(http.StatusContinue)
+	ErrSwitchingProtocols = newStatusError(http.StatusSwitchingProtocols)
+	ErrProcessing         = newStatusError(http.StatusProcessing)
+	ErrEarlyHints         = newStatusError(http.StatusEarlyHints)
+
+	ErrOK                   = newStatusError(http.StatusOK)
+	ErrCreated              = newStatusError(http.StatusCreated)
+	ErrAccepted             = newStatusError(http.StatusAccepted)
+	ErrNonAuthoritativeInfo = newStatusError(http.StatusNonAuthoritativeInfo)
+	ErrNoContent            = newStatusError(http.StatusNoContent)
+	ErrResetContent         = newStatusError(http.StatusResetContent)
+	ErrPartialContent       = newStatusError(http.StatusPartialContent)
+	ErrMultiStatus          = newStatusError(http.StatusMultiStatus)
+	ErrAlreadyReported      = newStatusError(http.StatusAlreadyReported)
+	ErrIMUsed               = newStatusError(http.StatusIMUsed)
+
+	ErrMultipleChoices   = newStatusError(http.StatusMultipleChoices)
+	ErrMovedPermanently  = newStatusError(http.StatusMovedPermanently)
+	ErrFound             = newStatusError(http.StatusFound)
+	ErrSeeOther          = newStatusError(http.StatusSeeOther)
+	ErrNotModified       = newStatusError(http.StatusNotModified)
+	ErrUseProxy          = newStatusError(http.StatusUseProxy)
+	ErrTemporaryRedirect = newStatusError(http.StatusTemporaryRedirect)
+	ErrPermanentRedirect = newStatusError(http.StatusPermanentRedirect)
+
+	ErrBadRequest                   = newStatusError(http.StatusBadRequest)
+	ErrUnauthorized                 = newStatusError(http.StatusUnauthorized)
+	ErrPaymentRequired              = newStatusError(http.StatusPaymentRequired)
+	ErrForbidden                    = newStatusError(http.StatusForbidden)
+	ErrNotFound                     = newStatusError(http.StatusNotFound)
+	ErrMethodNotAllowed             = newStatusError(http.StatusMethodNotAllowed)
+	ErrNotAcceptable                = newStatusError(http.StatusNotAcceptable)
+	ErrProxyAuthRequired            = newStatusError(http.StatusProxyAuthRequired)
+	ErrRequestTimeout               = newStatusError(http.StatusRequestTimeout)
+	ErrConflict                     = newStatusError(http.StatusConflict)
+	ErrGone                         = newStatusError(http.StatusGone)
+	ErrLengthRequired               = newStatusError(http.StatusLengthRequired)
+	ErrPreconditionFailed           = newStatusError(http.StatusPreconditionFailed)
+	ErrRequestEntityTooLarge        = newStatusError(http.StatusRequestEntityTooLarge)
+	ErrRequestURITooLong            = newStatusError(http.StatusRequestURITooLong)
+	ErrUnsupportedMediaType         = newStatusError(http.StatusUnsupportedMediaType)
+	ErrRequestedRangeNotSatisfiable = newStatusError(http.StatusRequestedRangeNotSatisfiable)
+	ErrExpectationFailed            = newStatusError(http.StatusExpectationFailed)
+	ErrTeapot                       = newStatusError(http.StatusTeapot)
+	ErrMisdirectedRequest           = newStatusError(http.StatusMisdirectedRequest)
+	ErrUnprocessableEntity          = newStatusError(http.StatusUnprocessableEntity)
+	ErrLocked                       = newStatusError(http.StatusLocked)
+	ErrFailedDependency             = newStatusError(http.StatusFailedDependency)
+	ErrTooEarly                     = newStatusError(http.StatusTooEarly)
+	ErrUpgradeRequired              = newStatusError(http.StatusUpgradeRequired)
+	ErrPreconditionRequired         = newStatusError(http.StatusPreconditionRequired)
+	ErrTooManyRequests              = newStatusError(http.StatusTooManyRequests)
+	ErrRequestHeaderFieldsTooLarge  = newStatusError(http.StatusRequestHeaderFieldsTooLarge)
+	ErrUnavailableForLegalReasons   = newStatusError(http.StatusUnavailableForLegalReasons)
+
+	ErrInternalServerError           = newStatusError(http.StatusInternalServerError)
+	ErrNotImplemented                = newStatusError(http.StatusNotImplemented)
+	ErrBadGateway                    = newStatusError(http.StatusBadGateway)
+	ErrServiceUnavailable            = newStatusError(http.StatusServiceUnavailable)
+	ErrGatewayTimeout                = newStatusError(http.StatusGatewayTimeout)
+	ErrHTTPVersionNotSupported       = newStatusError(http.StatusHTTPVersionNotSupported)
+	ErrVariantAlsoNegotiates         = newStatusError(http.StatusVariantAlsoNegotiates)
+	ErrInsufficientStorage           = newStatusError(http.StatusInsufficientStorage)
+	ErrLoopDetected                  = newStatusError(http.StatusLoopDetected)
+	ErrNotExtended                   = newStatusError(http.StatusNotExtended)
+	ErrNetworkAuthenticationRequired = newStatusError(http.StatusNetworkAuthenticationRequired)
+)
+
+// Is reports whether target is one of the sentinel errors above (or
+// ErrClientError, ErrServerError, ErrRetryable) that matches err's
+// StatusCode, so that callers can write
+// errors.Is(err, hterrors.ErrNotFound) or
+// errors.Is(err, hterrors.ErrServerError).
+func (err *StatusError) Is(target error) bool {
+	s, ok := target.(*statusError)
+	if !ok {
+		return false
+	}
+	return s.match(err.StatusCode)
+}
+
+// Retryable reports whether the request that produced err is generally
+// safe to retry, i.e. whether errors.Is(err, ErrRetryable) holds.
+func (err *StatusError) Retryable() bool {
+	return isRetryableStatus(err.StatusCode)
+}
+
+// Temporary reports whether err stems from a server-side condition
+// (a 5xx status) that may no longer hold on a subsequent request, as
+// opposed to a 4xx client error that will keep failing until the request
+// itself changes.
+func (err *StatusError) Temporary() bool {
+	return err.StatusCode >= 500 && err.StatusCode < 600
+}
+
+// RetryAfter returns how long to wait before retrying the request that
+// produced err, parsed from the Retry-After header (RFC 7231 §7.1.3), in
+// either its delta-seconds or HTTP-date form. It returns false if err has
+// no Retry-After header, or if it could not be parsed.
+func (err *StatusError) RetryAfter() (time.Duration, bool) {
+	v := err.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, e := strconv.Atoi(v); e == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, e := http.ParseTime(v); e == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}